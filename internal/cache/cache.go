@@ -0,0 +1,86 @@
+// Package cache provides a per-request memoization cache, keyed off
+// r.RequestCtx, so handlers that look up the same row (e.g. a team and its
+// members) multiple times within one HTTP request don't re-query it. It is
+// modeled on Gitea's WithCacheContext/GetContextData pattern.
+package cache
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// userValueKey is the fasthttp.RequestCtx UserValue key the store is
+// stashed under by Middleware.
+const userValueKey = "cache"
+
+// store is a type-partitioned map guarded by a single RWMutex, scoped to
+// the lifetime of one request.
+type store struct {
+	mu   sync.RWMutex
+	data map[reflect.Type]map[any]any
+}
+
+// Middleware installs a fresh per-request store on the context. It must run
+// before any handler calls GetOrLoad or Invalidate.
+func Middleware(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetUserValue(userValueKey, &store{data: make(map[reflect.Type]map[any]any)})
+		next(ctx)
+	}
+}
+
+// GetOrLoad returns the cached value of type T for key, calling loader and
+// caching the result on a miss. Errors from loader are never cached.
+func GetOrLoad[T any](ctx *fasthttp.RequestCtx, key any, loader func() (T, error)) (T, error) {
+	s := storeFrom(ctx)
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	s.mu.RLock()
+	if bucket, ok := s.data[t]; ok {
+		if v, ok := bucket[key]; ok {
+			s.mu.RUnlock()
+			return v.(T), nil
+		}
+	}
+	s.mu.RUnlock()
+
+	v, err := loader()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	s.mu.Lock()
+	if s.data[t] == nil {
+		s.data[t] = make(map[any]any)
+	}
+	s.data[t][key] = v
+	s.mu.Unlock()
+
+	return v, nil
+}
+
+// Invalidate drops the cached value of type T for key, e.g. after a write
+// that makes a previously cached lookup stale within the same request.
+func Invalidate[T any](ctx *fasthttp.RequestCtx, key any) {
+	s := storeFrom(ctx)
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bucket, ok := s.data[t]; ok {
+		delete(bucket, key)
+	}
+}
+
+// storeFrom returns the request's cache store, or a throwaway one if
+// Middleware wasn't installed so callers degrade to "always miss" instead
+// of panicking.
+func storeFrom(ctx *fasthttp.RequestCtx) *store {
+	if s, ok := ctx.UserValue(userValueKey).(*store); ok {
+		return s
+	}
+	return &store{data: make(map[reflect.Type]map[any]any)}
+}
@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// inviteLinkTTL is how long a signed invite link (hash/data pair) stays
+// valid, independent of whether the underlying InviteID has been rotated.
+const inviteLinkTTL = 7 * 24 * time.Hour
+
+// TeamInviteRequest represents a request to create a team invite
+type TeamInviteRequest struct {
+	Role          string     `json:"role"` // manager, agent
+	MaxUses       int        `json:"max_uses"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	AllowedEmails []string   `json:"allowed_emails"`
+}
+
+// TeamInviteResponse represents the join proofs for an invite link
+type TeamInviteResponse struct {
+	InviteID  string    `json:"invite_id"`
+	Hash      string    `json:"hash"`
+	Data      string    `json:"data"`
+	Role      string    `json:"role"`
+	MaxUses   int       `json:"max_uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// teamInviteInfo represents public-facing team info returned by the invite
+// preview endpoint, before the user has authenticated or joined.
+type teamInviteInfo struct {
+	TeamID      uuid.UUID `json:"team_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	MemberCount int       `json:"member_count"`
+	Role        string    `json:"role"`
+}
+
+// inviteSignedData is the payload embedded in the `data` query param and
+// authenticated by `hash`. InviteDBID pins it to the TeamInvite row it was
+// issued from, so callers can re-derive and enforce the admin-configured
+// MaxUses/ExpiresAt/AllowedEmails instead of trusting TeamID/Role alone. It
+// keeps working even after the invite's public InviteID token has been
+// rotated, since rotation only replaces InviteID on the same row.
+type inviteSignedData struct {
+	TeamID     uuid.UUID `json:"team_id"`
+	Role       string    `json:"role"`
+	CreatedAt  int64     `json:"created_at"`
+	InviteDBID uuid.UUID `json:"invite_db_id"`
+}
+
+// signInviteData encodes and HMAC-SHA256 signs an invite payload, returning
+// the base64 `data` and hex `hash` query values.
+func (a *App) signInviteData(payload inviteSignedData) (data string, hash string, err error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", "", err
+	}
+	data = base64.RawURLEncoding.EncodeToString(raw)
+	mac := hmac.New(sha256.New, a.inviteSecret())
+	mac.Write([]byte(data))
+	hash = hex.EncodeToString(mac.Sum(nil))
+	return data, hash, nil
+}
+
+// verifyInviteData validates the hash over data, decodes the payload and
+// rejects links whose embedded timestamp has expired.
+func (a *App) verifyInviteData(data, hash string) (*inviteSignedData, bool) {
+	if data == "" || hash == "" {
+		return nil, false
+	}
+
+	mac := hmac.New(sha256.New, a.inviteSecret())
+	mac.Write([]byte(data))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(hash)) {
+		return nil, false
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return nil, false
+	}
+
+	var payload inviteSignedData
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, false
+	}
+
+	if time.Since(time.Unix(payload.CreatedAt, 0)) > inviteLinkTTL {
+		return nil, false
+	}
+
+	return &payload, true
+}
+
+// inviteSecret returns the server-side HMAC secret used to sign invite
+// links.
+func (a *App) inviteSecret() []byte {
+	return []byte(a.Config.InviteHMACSecret)
+}
+
+// loadInviteFromPayload re-derives the TeamInvite row a signed payload was
+// issued from, so its current MaxUses/ExpiresAt/AllowedEmails are enforced
+// rather than just the payload's TeamID/Role and the link's overall TTL.
+func (a *App) loadInviteFromPayload(payload *inviteSignedData) (*models.TeamInvite, error) {
+	var invite models.TeamInvite
+	if err := a.DB.Where("id = ? AND team_id = ?", payload.InviteDBID, payload.TeamID).First(&invite).Error; err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// generateInviteID returns a random URL-safe token suitable for use as an
+// invite_id path segment.
+func generateInviteID() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// isTeamManagerOrAdmin reports whether the user is an org admin or a
+// manager of the given team.
+func isTeamManagerOrAdmin(team *models.Team, userID uuid.UUID, userRole string) bool {
+	if userRole == "admin" {
+		return true
+	}
+	for _, m := range team.Members {
+		if m.UserID == userID && m.Role == "manager" {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateTeamInvite creates a new invite for a team (admin or team manager)
+func (a *App) CreateTeamInvite(r *fastglue.Request) error {
+	orgID := r.RequestCtx.UserValue("organization_id").(uuid.UUID)
+	userID := r.RequestCtx.UserValue("user_id").(uuid.UUID)
+	userRole := r.RequestCtx.UserValue("role").(string)
+	teamIDStr := r.RequestCtx.UserValue("id").(string)
+
+	teamID, err := uuid.Parse(teamIDStr)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid team ID", nil, "")
+	}
+
+	var team models.Team
+	if err := a.DB.Where("id = ? AND organization_id = ?", teamID, orgID).
+		Preload("Members").First(&team).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
+	}
+
+	if !isTeamManagerOrAdmin(&team, userID, userRole) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Only admins or team managers can create invites", nil, "")
+	}
+
+	var req TeamInviteRequest
+	if err := r.Decode(&req, "json"); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid request body", nil, "")
+	}
+
+	role := req.Role
+	if role == "" {
+		role = "agent"
+	}
+	if role != "manager" && role != "agent" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid role. Must be 'manager' or 'agent'", nil, "")
+	}
+	if userRole != "admin" && role == "manager" {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Only admins can create manager invites", nil, "")
+	}
+
+	inviteID, err := generateInviteID()
+	if err != nil {
+		a.Log.Error("Failed to generate invite token", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create invite", nil, "")
+	}
+
+	invite := models.TeamInvite{
+		TeamID:        teamID,
+		InviteID:      inviteID,
+		Role:          role,
+		MaxUses:       req.MaxUses,
+		ExpiresAt:     req.ExpiresAt,
+		AllowedEmails: strings.Join(req.AllowedEmails, ","),
+	}
+
+	if err := a.DB.Create(&invite).Error; err != nil {
+		a.Log.Error("Failed to create team invite", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create invite", nil, "")
+	}
+
+	data, hash, err := a.signInviteData(inviteSignedData{TeamID: teamID, Role: role, CreatedAt: time.Now().Unix(), InviteDBID: invite.ID})
+	if err != nil {
+		a.Log.Error("Failed to sign invite data", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to create invite", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]interface{}{"invite": TeamInviteResponse{
+		InviteID:  invite.InviteID,
+		Hash:      hash,
+		Data:      data,
+		Role:      invite.Role,
+		MaxUses:   invite.MaxUses,
+		ExpiresAt: invite.ExpiresAt,
+	}})
+}
+
+// RotateTeamInvite regenerates the InviteID of the team's most recently
+// created invite, invalidating unsigned invite_id links to that invite.
+// Other invites of the team keep their InviteID, so their unsigned links
+// stay valid. Previously issued signed hash/data links keep working until
+// their embedded timestamp expires.
+func (a *App) RotateTeamInvite(r *fastglue.Request) error {
+	orgID := r.RequestCtx.UserValue("organization_id").(uuid.UUID)
+	userID := r.RequestCtx.UserValue("user_id").(uuid.UUID)
+	userRole := r.RequestCtx.UserValue("role").(string)
+	teamIDStr := r.RequestCtx.UserValue("id").(string)
+
+	teamID, err := uuid.Parse(teamIDStr)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid team ID", nil, "")
+	}
+
+	var team models.Team
+	if err := a.DB.Where("id = ? AND organization_id = ?", teamID, orgID).
+		Preload("Members").First(&team).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
+	}
+
+	if !isTeamManagerOrAdmin(&team, userID, userRole) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Only admins or team managers can rotate invites", nil, "")
+	}
+
+	var invite models.TeamInvite
+	if err := a.DB.Where("team_id = ?", teamID).Order("created_at DESC").First(&invite).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "No invite found for this team", nil, "")
+	}
+
+	newInviteID, err := generateInviteID()
+	if err != nil {
+		a.Log.Error("Failed to generate invite token", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to rotate invite", nil, "")
+	}
+
+	invite.InviteID = newInviteID
+	if err := a.DB.Save(&invite).Error; err != nil {
+		a.Log.Error("Failed to rotate team invite", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to rotate invite", nil, "")
+	}
+
+	data, hash, err := a.signInviteData(inviteSignedData{TeamID: teamID, Role: invite.Role, CreatedAt: time.Now().Unix(), InviteDBID: invite.ID})
+	if err != nil {
+		a.Log.Error("Failed to sign invite data", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to rotate invite", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]interface{}{"invite": TeamInviteResponse{
+		InviteID:  invite.InviteID,
+		Hash:      hash,
+		Data:      data,
+		Role:      invite.Role,
+		MaxUses:   invite.MaxUses,
+		ExpiresAt: invite.ExpiresAt,
+	}})
+}
+
+// GetTeamInvitePreview returns public team info for an invite link, without
+// requiring authentication. It accepts either the invite_id path segment or
+// a signed `hash`/`data` query pair, the latter remaining valid across
+// InviteID rotations.
+func (a *App) GetTeamInvitePreview(r *fastglue.Request) error {
+	inviteIDStr := r.RequestCtx.UserValue("invite_id").(string)
+
+	var team models.Team
+
+	if payload, ok := a.verifyInviteData(string(r.RequestCtx.QueryArgs().Peek("data")), string(r.RequestCtx.QueryArgs().Peek("hash"))); ok {
+		if err := a.DB.Preload("Members").First(&team, "id = ?", payload.TeamID).Error; err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
+		}
+		return r.SendEnvelope(map[string]interface{}{"team": teamInviteInfo{
+			TeamID:      team.ID,
+			Name:        team.Name,
+			Description: team.Description,
+			MemberCount: len(team.Members),
+			Role:        payload.Role,
+		}})
+	}
+
+	var invite models.TeamInvite
+	if err := a.DB.Where("invite_id = ?", inviteIDStr).First(&invite).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Invite not found", nil, "")
+	}
+	if invite.ExpiresAt != nil && invite.ExpiresAt.Before(time.Now()) {
+		return r.SendErrorEnvelope(fasthttp.StatusGone, "Invite has expired", nil, "")
+	}
+	if invite.MaxUses > 0 && invite.Uses >= invite.MaxUses {
+		return r.SendErrorEnvelope(fasthttp.StatusGone, "Invite has reached its usage limit", nil, "")
+	}
+
+	if err := a.DB.Preload("Members").First(&team, "id = ?", invite.TeamID).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]interface{}{"team": teamInviteInfo{
+		TeamID:      team.ID,
+		Name:        team.Name,
+		Description: team.Description,
+		MemberCount: len(team.Members),
+		Role:        invite.Role,
+	}})
+}
+
+// AcceptTeamInvite joins the authenticated user to a team via an invite_id
+// or a signed hash/data pair.
+func (a *App) AcceptTeamInvite(r *fastglue.Request) error {
+	orgID := r.RequestCtx.UserValue("organization_id").(uuid.UUID)
+	userID := r.RequestCtx.UserValue("user_id").(uuid.UUID)
+	inviteIDStr := r.RequestCtx.UserValue("invite_id").(string)
+
+	var user models.User
+	if err := a.DB.Where("id = ? AND organization_id = ?", userID, orgID).First(&user).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "User not found", nil, "")
+	}
+
+	var invite *models.TeamInvite
+	if payload, ok := a.verifyInviteData(string(r.RequestCtx.QueryArgs().Peek("data")), string(r.RequestCtx.QueryArgs().Peek("hash"))); ok {
+		inv, err := a.loadInviteFromPayload(payload)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Invite not found", nil, "")
+		}
+		invite = inv
+	} else {
+		var inv models.TeamInvite
+		if err := a.DB.Where("invite_id = ?", inviteIDStr).First(&inv).Error; err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Invite not found", nil, "")
+		}
+		invite = &inv
+	}
+
+	if invite.ExpiresAt != nil && invite.ExpiresAt.Before(time.Now()) {
+		return r.SendErrorEnvelope(fasthttp.StatusGone, "Invite has expired", nil, "")
+	}
+	if invite.MaxUses > 0 && invite.Uses >= invite.MaxUses {
+		return r.SendErrorEnvelope(fasthttp.StatusGone, "Invite has reached its usage limit", nil, "")
+	}
+	if invite.AllowedEmails != "" && !emailInAllowList(user.Email, invite.AllowedEmails) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "This invite is not valid for your email address", nil, "")
+	}
+	teamID, role := invite.TeamID, invite.Role
+
+	var team models.Team
+	if err := a.DB.Where("id = ? AND organization_id = ?", teamID, orgID).
+		Preload("Members").First(&team).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
+	}
+
+	if !isTeamEmailAllowed(&user, &team) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "team_domain_not_allowed", nil, "")
+	}
+
+	for _, m := range team.Members {
+		if m.UserID == userID {
+			return r.SendErrorEnvelope(fasthttp.StatusConflict, "User is already a member of this team", nil, "")
+		}
+	}
+
+	// Only consume a use once every other check has passed, so a rejected
+	// join (already a member, domain not allowed) doesn't burn a max_uses
+	// slot a legitimate joiner needed.
+	invite.Uses++
+	if err := a.DB.Save(invite).Error; err != nil {
+		a.Log.Error("Failed to record invite use", "error", err)
+	}
+
+	member := models.TeamMember{
+		TeamID: teamID,
+		UserID: userID,
+		Role:   role,
+	}
+	if err := a.DB.Create(&member).Error; err != nil {
+		a.Log.Error("Failed to accept team invite", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to join team", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]interface{}{"member": TeamMemberResponse{
+		ID:          member.ID,
+		UserID:      member.UserID,
+		FullName:    user.FullName,
+		Email:       user.Email,
+		Role:        member.Role,
+		IsAvailable: user.IsAvailable,
+	}})
+}
+
+// emailInAllowList reports whether email appears in a comma-separated list.
+func emailInAllowList(email, list string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	for _, e := range strings.Split(list, ",") {
+		if strings.ToLower(strings.TrimSpace(e)) == email {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/cache"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// UpdateTeamMemberRoleRequest represents a member role-change request
+type UpdateTeamMemberRoleRequest struct {
+	Role string `json:"role" validate:"required"` // manager, agent
+}
+
+// teamMemberRoleUpdatedEvent is published on the websocket/event bus when a
+// member's role changes, mirroring the conversation-assignment events.
+type teamMemberRoleUpdatedEvent struct {
+	TeamID  uuid.UUID `json:"team_id"`
+	UserID  uuid.UUID `json:"user_id"`
+	OldRole string    `json:"old_role"`
+	NewRole string    `json:"new_role"`
+}
+
+// UpdateTeamMemberRole changes a member's role in place (admin or team
+// manager), preserving LastAssignedAt and the member's position in a
+// round_robin rotation.
+func (a *App) UpdateTeamMemberRole(r *fastglue.Request) error {
+	orgID := r.RequestCtx.UserValue("organization_id").(uuid.UUID)
+	userID := r.RequestCtx.UserValue("user_id").(uuid.UUID)
+	userRole := r.RequestCtx.UserValue("role").(string)
+	teamIDStr := r.RequestCtx.UserValue("id").(string)
+	memberUserIDStr := r.RequestCtx.UserValue("user_id_param").(string)
+
+	teamID, err := uuid.Parse(teamIDStr)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid team ID", nil, "")
+	}
+
+	memberUserID, err := uuid.Parse(memberUserIDStr)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid user ID", nil, "")
+	}
+
+	var team models.Team
+	if err := a.DB.Where("id = ? AND organization_id = ?", teamID, orgID).
+		Preload("Members").First(&team).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
+	}
+
+	if !isTeamManagerOrAdmin(&team, userID, userRole) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Only admins or team managers can change member roles", nil, "")
+	}
+
+	var req UpdateTeamMemberRoleRequest
+	if err := r.Decode(&req, "json"); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid request body", nil, "")
+	}
+	if req.Role != "manager" && req.Role != "agent" {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid role. Must be 'manager' or 'agent'", nil, "")
+	}
+	if userRole != "admin" && req.Role == "manager" {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Only admins can promote members to manager", nil, "")
+	}
+
+	member, err := a.loadTeamMember(r, teamID, memberUserID)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Member not found in team", nil, "")
+	}
+
+	oldRole := member.Role
+	if oldRole == req.Role {
+		return r.SendEnvelope(map[string]interface{}{"member": TeamMemberResponse{
+			ID:             member.ID,
+			UserID:         member.UserID,
+			Role:           member.Role,
+			LastAssignedAt: member.LastAssignedAt,
+		}})
+	}
+
+	// The last remaining manager of an active team cannot be demoted.
+	if oldRole == "manager" && req.Role != "manager" && team.IsActive {
+		managerCount := 0
+		for _, m := range team.Members {
+			if m.Role == "manager" {
+				managerCount++
+			}
+		}
+		if managerCount <= 1 {
+			return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Cannot demote the last remaining manager of an active team", nil, "")
+		}
+	}
+
+	member.Role = req.Role
+	if err := a.DB.Save(member).Error; err != nil {
+		a.Log.Error("Failed to update team member role", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update role", nil, "")
+	}
+	cache.Invalidate[*models.TeamMember](r.RequestCtx, teamMemberKey{TeamID: teamID, UserID: memberUserID})
+
+	a.WS.Broadcast(orgID, "team_member_role_updated", teamMemberRoleUpdatedEvent{
+		TeamID:  teamID,
+		UserID:  memberUserID,
+		OldRole: oldRole,
+		NewRole: member.Role,
+	})
+
+	return r.SendEnvelope(map[string]interface{}{"member": TeamMemberResponse{
+		ID:             member.ID,
+		UserID:         member.UserID,
+		Role:           member.Role,
+		LastAssignedAt: member.LastAssignedAt,
+	}})
+}
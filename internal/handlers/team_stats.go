@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/models"
+	"github.com/valyala/fasthttp"
+	"github.com/zerodha/fastglue"
+)
+
+// TeamStatsResponse represents workload and availability metrics for a team
+type TeamStatsResponse struct {
+	TotalMembers       int                  `json:"total_members"`
+	ActiveMembers      int                  `json:"active_members"`
+	AvailableMembers   int                  `json:"available_members"`
+	RoleCounts         map[string]int       `json:"role_counts"`
+	MemberWorkloads    []TeamMemberWorkload `json:"member_workloads"`
+	AssignmentHistogram []AssignmentBucket  `json:"assignment_histogram"`
+
+	// WorkloadStdDev is the standard deviation of open-conversation counts
+	// across members, only populated for load_balanced teams.
+	WorkloadStdDev *float64 `json:"workload_std_dev,omitempty"`
+
+	// RoundRobinNextUp is the member at the head of the round-robin
+	// rotation (min LastAssignedAt), only populated for round_robin teams.
+	RoundRobinNextUp *uuid.UUID `json:"round_robin_next_up,omitempty"`
+}
+
+// TeamMemberWorkload represents a single member's assignment workload
+type TeamMemberWorkload struct {
+	UserID            uuid.UUID  `json:"user_id"`
+	FullName          string     `json:"full_name"`
+	Role              string     `json:"role"`
+	OpenConversations int        `json:"open_conversations"`
+	LastAssignedAt    *time.Time `json:"last_assigned_at,omitempty"`
+}
+
+// AssignmentBucket is one day's worth of the assignment-distribution
+// histogram.
+type AssignmentBucket struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// teamStatsWindowDays bounds how far back the assignment histogram looks.
+const teamStatsWindowDays = 14
+
+// GetTeamStats returns workload and availability metrics for a team
+func (a *App) GetTeamStats(r *fastglue.Request) error {
+	orgID := r.RequestCtx.UserValue("organization_id").(uuid.UUID)
+	userID := r.RequestCtx.UserValue("user_id").(uuid.UUID)
+	userRole := r.RequestCtx.UserValue("role").(string)
+	teamIDStr := r.RequestCtx.UserValue("id").(string)
+
+	teamID, err := uuid.Parse(teamIDStr)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid team ID", nil, "")
+	}
+
+	var team models.Team
+	if err := a.DB.Where("id = ? AND organization_id = ?", teamID, orgID).
+		Preload("Members").First(&team).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
+	}
+
+	if userRole != "admin" {
+		hasAccess := false
+		for _, m := range team.Members {
+			if m.UserID == userID {
+				hasAccess = true
+				break
+			}
+		}
+		if !hasAccess {
+			return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Access denied", nil, "")
+		}
+	}
+
+	stats, err := a.buildTeamStats(teamID, team.AssignmentStrategy)
+	if err != nil {
+		a.Log.Error("Failed to compute team stats", "error", err)
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to compute team stats", nil, "")
+	}
+
+	return r.SendEnvelope(map[string]interface{}{"stats": stats})
+}
+
+// buildTeamStats aggregates member workload and availability metrics for a
+// single team. It is kept separate from the handler so a future
+// all-teams dashboard endpoint can call it per team without re-doing the
+// SQL aggregation.
+func (a *App) buildTeamStats(teamID uuid.UUID, assignmentStrategy string) (*TeamStatsResponse, error) {
+	var roleCounts []struct {
+		Role  string
+		Count int
+	}
+	if err := a.DB.Table("team_members").
+		Select("role, COUNT(*) as count").
+		Where("team_id = ?", teamID).
+		Group("role").Scan(&roleCounts).Error; err != nil {
+		return nil, err
+	}
+
+	var availability struct {
+		Total     int
+		Active    int
+		Available int
+	}
+	if err := a.DB.Table("team_members").
+		Joins("JOIN users ON users.id = team_members.user_id").
+		Where("team_members.team_id = ?", teamID).
+		Select(`
+			COUNT(*) as total,
+			COUNT(*) FILTER (WHERE users.is_active) as active,
+			COUNT(*) FILTER (WHERE users.is_available) as available
+		`).Scan(&availability).Error; err != nil {
+		return nil, err
+	}
+
+	var workloads []TeamMemberWorkload
+	if err := a.DB.Table("team_members").
+		Joins("JOIN users ON users.id = team_members.user_id").
+		Joins(`LEFT JOIN conversations ON conversations.assigned_to = team_members.user_id
+			AND conversations.status NOT IN ('resolved', 'closed')`).
+		Where("team_members.team_id = ?", teamID).
+		Select(`
+			team_members.user_id as user_id,
+			users.full_name as full_name,
+			team_members.role as role,
+			team_members.last_assigned_at as last_assigned_at,
+			COUNT(conversations.id) as open_conversations
+		`).
+		Group("team_members.user_id, users.full_name, team_members.role, team_members.last_assigned_at").
+		Scan(&workloads).Error; err != nil {
+		return nil, err
+	}
+
+	var histogram []AssignmentBucket
+	if err := a.DB.Table("conversations").
+		Joins("JOIN team_members ON team_members.user_id = conversations.assigned_to AND team_members.team_id = ?", teamID).
+		Where(fmt.Sprintf("conversations.assigned_at >= NOW() - INTERVAL '%d days'", teamStatsWindowDays)).
+		Select("DATE(conversations.assigned_at) as date, COUNT(*) as count").
+		Group("DATE(conversations.assigned_at)").
+		Order("date ASC").
+		Scan(&histogram).Error; err != nil {
+		return nil, err
+	}
+
+	resp := &TeamStatsResponse{
+		TotalMembers:         availability.Total,
+		ActiveMembers:        availability.Active,
+		AvailableMembers:     availability.Available,
+		RoleCounts:           make(map[string]int, len(roleCounts)),
+		MemberWorkloads:      workloads,
+		AssignmentHistogram:  histogram,
+	}
+	for _, rc := range roleCounts {
+		resp.RoleCounts[rc.Role] = rc.Count
+	}
+
+	switch assignmentStrategy {
+	case "load_balanced":
+		stddev := workloadStdDev(workloads)
+		resp.WorkloadStdDev = &stddev
+	case "round_robin":
+		if next := roundRobinNextUp(workloads); next != uuid.Nil {
+			resp.RoundRobinNextUp = &next
+		}
+	}
+
+	return resp, nil
+}
+
+// workloadStdDev computes the standard deviation of open-conversation
+// counts across members, used to flag imbalance in load_balanced teams.
+func workloadStdDev(workloads []TeamMemberWorkload) float64 {
+	if len(workloads) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, w := range workloads {
+		sum += float64(w.OpenConversations)
+	}
+	mean := sum / float64(len(workloads))
+
+	var variance float64
+	for _, w := range workloads {
+		d := float64(w.OpenConversations) - mean
+		variance += d * d
+	}
+	variance /= float64(len(workloads))
+
+	return math.Sqrt(variance)
+}
+
+// roundRobinNextUp returns the member with the oldest (or absent)
+// LastAssignedAt — the next member in line for a round_robin team.
+func roundRobinNextUp(workloads []TeamMemberWorkload) uuid.UUID {
+	var next uuid.UUID
+	var oldest *time.Time
+
+	for _, w := range workloads {
+		if w.LastAssignedAt == nil {
+			return w.UserID
+		}
+		if oldest == nil || w.LastAssignedAt.Before(*oldest) {
+			oldest = w.LastAssignedAt
+			next = w.UserID
+		}
+	}
+
+	return next
+}
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shridarpatil/whatomate/internal/cache"
 	"github.com/shridarpatil/whatomate/internal/models"
 	"github.com/valyala/fasthttp"
 	"github.com/zerodha/fastglue"
+	"gorm.io/gorm"
 )
 
 // TeamRequest represents create/update team request
@@ -15,12 +19,22 @@ type TeamRequest struct {
 	Description        string `json:"description"`
 	AssignmentStrategy string `json:"assignment_strategy"` // round_robin, load_balanced, manual
 	IsActive           bool   `json:"is_active"`
+
+	// AllowedDomains is a comma/space-separated list of email domains
+	// (e.g. "corp.example.com acme.com") allowed to join this team.
+	// An empty list allows any domain.
+	AllowedDomains string `json:"allowed_domains"`
 }
 
 // TeamMemberRequest represents add member request
 type TeamMemberRequest struct {
 	UserID string `json:"user_id" validate:"required"`
 	Role   string `json:"role"` // manager, agent
+
+	// Invite join proofs, accepted in lieu of admin/manager auth.
+	InviteID   string `json:"invite_id"`
+	InviteHash string `json:"hash"`
+	InviteData string `json:"data"`
 }
 
 // TeamResponse represents team in API response
@@ -30,6 +44,7 @@ type TeamResponse struct {
 	Description        string               `json:"description"`
 	AssignmentStrategy string               `json:"assignment_strategy"`
 	IsActive           bool                 `json:"is_active"`
+	AllowedDomains     string               `json:"allowed_domains"`
 	MemberCount        int                  `json:"member_count"`
 	Members            []TeamMemberResponse `json:"members,omitempty"`
 	CreatedAt          time.Time            `json:"created_at"`
@@ -47,6 +62,46 @@ type TeamMemberResponse struct {
 	LastAssignedAt *time.Time `json:"last_assigned_at,omitempty"`
 }
 
+// defaultPerPage and maxPerPage bound list endpoint page sizes.
+const (
+	defaultPerPage = 20
+	maxPerPage     = 200
+)
+
+// listTeamsSortColumns maps the `sort` query param to a safe SQL ORDER BY
+// expression.
+var listTeamsSortColumns = map[string]string{
+	"name":         "teams.name ASC",
+	"created_at":   "teams.created_at DESC",
+	"member_count": "member_count DESC",
+}
+
+// teamListRow is a models.Team plus the member_count computed by the list
+// query's subquery, scanned directly instead of hydrating via Preload.
+type teamListRow struct {
+	models.Team
+	MemberCount int `gorm:"column:member_count"`
+}
+
+// parsePageParams reads page/per_page query params, defaulting to page 1
+// and defaultPerPage, capped at maxPerPage.
+func parsePageParams(r *fastglue.Request) (page, perPage int) {
+	page = 1
+	if v, err := strconv.Atoi(string(r.RequestCtx.QueryArgs().Peek("page"))); err == nil && v > 0 {
+		page = v
+	}
+
+	perPage = defaultPerPage
+	if v, err := strconv.Atoi(string(r.RequestCtx.QueryArgs().Peek("per_page"))); err == nil && v > 0 {
+		perPage = v
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	return page, perPage
+}
+
 // ListTeams returns teams based on user access
 // Admin: all teams, Manager: their teams, Agent: their teams
 func (a *App) ListTeams(r *fastglue.Request) error {
@@ -54,32 +109,51 @@ func (a *App) ListTeams(r *fastglue.Request) error {
 	userID := r.RequestCtx.UserValue("user_id").(uuid.UUID)
 	userRole := r.RequestCtx.UserValue("role").(string)
 
-	var teams []models.Team
+	page, perPage := parsePageParams(r)
+	q := string(r.RequestCtx.QueryArgs().Peek("q"))
 
-	if userRole == "admin" {
-		// Admin sees all teams
-		if err := a.DB.Where("organization_id = ?", orgID).
-			Preload("Members").Preload("Members.User").
-			Order("name ASC").Find(&teams).Error; err != nil {
-			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list teams", nil, "")
-		}
-	} else {
+	order, ok := listTeamsSortColumns[string(r.RequestCtx.QueryArgs().Peek("sort"))]
+	if !ok {
+		order = listTeamsSortColumns["name"]
+	}
+
+	query := a.DB.Table("teams").
+		Select("teams.*, (SELECT COUNT(*) FROM team_members WHERE team_members.team_id = teams.id) as member_count").
+		Where("teams.organization_id = ?", orgID)
+
+	if userRole != "admin" {
 		// Managers and agents only see teams they belong to
-		if err := a.DB.Joins("JOIN team_members ON team_members.team_id = teams.id").
-			Where("teams.organization_id = ? AND team_members.user_id = ?", orgID, userID).
-			Preload("Members").Preload("Members.User").
-			Order("teams.name ASC").Find(&teams).Error; err != nil {
-			return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list teams", nil, "")
-		}
+		query = query.Joins("JOIN team_members ON team_members.team_id = teams.id AND team_members.user_id = ?", userID)
+	}
+
+	if q != "" {
+		query = query.Where("teams.name ILIKE ?", "%"+q+"%")
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list teams", nil, "")
+	}
+
+	var rows []teamListRow
+	if err := query.Order(order).
+		Offset((page - 1) * perPage).Limit(perPage).
+		Scan(&rows).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list teams", nil, "")
 	}
 
-	// Build response
-	response := make([]TeamResponse, len(teams))
-	for i, t := range teams {
-		response[i] = buildTeamResponse(&t, false)
+	response := make([]TeamResponse, len(rows))
+	for i, row := range rows {
+		response[i] = buildTeamResponse(&row.Team, false)
+		response[i].MemberCount = row.MemberCount
 	}
 
-	return r.SendEnvelope(map[string]interface{}{"teams": response})
+	return r.SendEnvelope(map[string]interface{}{
+		"teams":    response,
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+	})
 }
 
 // GetTeam returns a single team with members
@@ -94,10 +168,8 @@ func (a *App) GetTeam(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid team ID", nil, "")
 	}
 
-	var team models.Team
-	if err := a.DB.Where("id = ? AND organization_id = ?", teamID, orgID).
-		Preload("Members").Preload("Members.User").
-		First(&team).Error; err != nil {
+	team, err := a.loadTeamWithMembers(r, teamID, orgID)
+	if err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
 	}
 
@@ -115,7 +187,54 @@ func (a *App) GetTeam(r *fastglue.Request) error {
 		}
 	}
 
-	return r.SendEnvelope(map[string]interface{}{"team": buildTeamResponse(&team, true)})
+	return r.SendEnvelope(map[string]interface{}{"team": buildTeamResponse(team, true)})
+}
+
+// loadTeamWithMembers fetches a team (with Members and Members.User
+// preloaded) through the per-request cache, so handlers that check access
+// and then operate on the same team don't issue the query twice.
+func (a *App) loadTeamWithMembers(r *fastglue.Request, teamID, orgID uuid.UUID) (*models.Team, error) {
+	return cache.GetOrLoad(r.RequestCtx, teamID, func() (*models.Team, error) {
+		var team models.Team
+		if err := a.DB.Where("id = ? AND organization_id = ?", teamID, orgID).
+			Preload("Members").Preload("Members.User").
+			First(&team).Error; err != nil {
+			return nil, err
+		}
+		return &team, nil
+	})
+}
+
+// loadOrgUser fetches a user scoped to an organization through the
+// per-request cache, so handlers that look the same user up more than once
+// (e.g. to validate and then to build a response) don't re-query it.
+func (a *App) loadOrgUser(r *fastglue.Request, userID, orgID uuid.UUID) (*models.User, error) {
+	return cache.GetOrLoad(r.RequestCtx, userID, func() (*models.User, error) {
+		var user models.User
+		if err := a.DB.Where("id = ? AND organization_id = ?", userID, orgID).First(&user).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	})
+}
+
+// teamMemberKey identifies a single team_members row in the per-request
+// cache; TeamID+UserID is the membership's natural key.
+type teamMemberKey struct {
+	TeamID uuid.UUID
+	UserID uuid.UUID
+}
+
+// loadTeamMember fetches a team membership row through the per-request
+// cache, so handlers that look it up more than once don't re-query it.
+func (a *App) loadTeamMember(r *fastglue.Request, teamID, userID uuid.UUID) (*models.TeamMember, error) {
+	return cache.GetOrLoad(r.RequestCtx, teamMemberKey{TeamID: teamID, UserID: userID}, func() (*models.TeamMember, error) {
+		var member models.TeamMember
+		if err := a.DB.Where("team_id = ? AND user_id = ?", teamID, userID).First(&member).Error; err != nil {
+			return nil, err
+		}
+		return &member, nil
+	})
 }
 
 // CreateTeam creates a new team (admin only)
@@ -151,6 +270,7 @@ func (a *App) CreateTeam(r *fastglue.Request) error {
 		Description:        req.Description,
 		AssignmentStrategy: strategy,
 		IsActive:           true,
+		AllowedDomains:     normalizeAllowedDomains(req.AllowedDomains),
 	}
 
 	if err := a.DB.Create(&team).Error; err != nil {
@@ -173,9 +293,8 @@ func (a *App) UpdateTeam(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid team ID", nil, "")
 	}
 
-	var team models.Team
-	if err := a.DB.Where("id = ? AND organization_id = ?", teamID, orgID).
-		Preload("Members").First(&team).Error; err != nil {
+	team, err := a.loadTeamWithMembers(r, teamID, orgID)
+	if err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
 	}
 
@@ -204,6 +323,7 @@ func (a *App) UpdateTeam(r *fastglue.Request) error {
 	}
 	team.Description = req.Description
 	team.IsActive = req.IsActive
+	team.AllowedDomains = normalizeAllowedDomains(req.AllowedDomains)
 
 	if req.AssignmentStrategy != "" {
 		if req.AssignmentStrategy != "round_robin" && req.AssignmentStrategy != "load_balanced" && req.AssignmentStrategy != "manual" {
@@ -212,12 +332,13 @@ func (a *App) UpdateTeam(r *fastglue.Request) error {
 		team.AssignmentStrategy = req.AssignmentStrategy
 	}
 
-	if err := a.DB.Save(&team).Error; err != nil {
+	if err := a.DB.Save(team).Error; err != nil {
 		a.Log.Error("Failed to update team", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to update team", nil, "")
 	}
+	cache.Invalidate[*models.Team](r.RequestCtx, teamID)
 
-	return r.SendEnvelope(map[string]interface{}{"team": buildTeamResponse(&team, false)})
+	return r.SendEnvelope(map[string]interface{}{"team": buildTeamResponse(team, false)})
 }
 
 // DeleteTeam deletes a team (admin only)
@@ -255,7 +376,25 @@ func (a *App) DeleteTeam(r *fastglue.Request) error {
 	return r.SendEnvelope(map[string]string{"message": "Team deleted"})
 }
 
-// ListTeamMembers lists members of a team
+// listTeamMembersSortColumns maps the `sort` query param to a safe SQL
+// ORDER BY expression.
+var listTeamMembersSortColumns = map[string]string{
+	"name":       "users.full_name ASC",
+	"created_at": "team_members.created_at DESC",
+}
+
+// teamMemberListRow is the flat shape of a members-list query row.
+type teamMemberListRow struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	FullName       string
+	Email          string
+	Role           string
+	IsAvailable    bool
+	LastAssignedAt *time.Time
+}
+
+// ListTeamMembers lists members of a team, with pagination and filtering.
 func (a *App) ListTeamMembers(r *fastglue.Request) error {
 	orgID := r.RequestCtx.UserValue("organization_id").(uuid.UUID)
 	userID := r.RequestCtx.UserValue("user_id").(uuid.UUID)
@@ -267,15 +406,155 @@ func (a *App) ListTeamMembers(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid team ID", nil, "")
 	}
 
-	// Verify team exists and user has access
-	var team models.Team
-	if err := a.DB.Where("id = ? AND organization_id = ?", teamID, orgID).
-		Preload("Members").Preload("Members.User").
-		First(&team).Error; err != nil {
+	if err := a.verifyTeamAccess(r, teamID, orgID, userID, userRole); err != nil {
+		return err
+	}
+
+	page, perPage := parsePageParams(r)
+	q := string(r.RequestCtx.QueryArgs().Peek("q"))
+	role := string(r.RequestCtx.QueryArgs().Peek("role"))
+
+	order, ok := listTeamMembersSortColumns[string(r.RequestCtx.QueryArgs().Peek("sort"))]
+	if !ok {
+		order = listTeamMembersSortColumns["name"]
+	}
+
+	query := a.DB.Table("team_members").
+		Joins("JOIN users ON users.id = team_members.user_id").
+		Where("team_members.team_id = ?", teamID)
+
+	if q != "" {
+		query = query.Where("users.email ILIKE ? OR users.full_name ILIKE ?", "%"+q+"%", "%"+q+"%")
+	}
+	if role != "" {
+		query = query.Where("team_members.role = ?", role)
+	}
+	if v := string(r.RequestCtx.QueryArgs().Peek("is_available")); v != "" {
+		query = query.Where("users.is_available = ?", v == "true")
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list team members", nil, "")
+	}
+
+	var rows []teamMemberListRow
+	if err := query.Order(order).
+		Offset((page - 1) * perPage).Limit(perPage).
+		Select(`
+			team_members.id as id,
+			team_members.user_id as user_id,
+			users.full_name as full_name,
+			users.email as email,
+			team_members.role as role,
+			users.is_available as is_available,
+			team_members.last_assigned_at as last_assigned_at
+		`).
+		Scan(&rows).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to list team members", nil, "")
+	}
+
+	members := make([]TeamMemberResponse, len(rows))
+	for i, row := range rows {
+		members[i] = TeamMemberResponse{
+			ID:             row.ID,
+			UserID:         row.UserID,
+			FullName:       row.FullName,
+			Email:          row.Email,
+			Role:           row.Role,
+			IsAvailable:    row.IsAvailable,
+			LastAssignedAt: row.LastAssignedAt,
+		}
+	}
+
+	return r.SendEnvelope(map[string]interface{}{
+		"members":  members,
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+	})
+}
+
+// BatchTeamMembersRequest requests hydrated members for a caller-supplied
+// list of user IDs, e.g. for a UI selector that only has IDs on hand.
+type BatchTeamMembersRequest struct {
+	UserIDs []string `json:"user_ids" validate:"required"`
+}
+
+// ListTeamMembersByIDs returns TeamMemberResponse for a caller-supplied list
+// of user IDs within a team (batched hydration for UI selectors).
+func (a *App) ListTeamMembersByIDs(r *fastglue.Request) error {
+	orgID := r.RequestCtx.UserValue("organization_id").(uuid.UUID)
+	userID := r.RequestCtx.UserValue("user_id").(uuid.UUID)
+	userRole := r.RequestCtx.UserValue("role").(string)
+	teamIDStr := r.RequestCtx.UserValue("id").(string)
+
+	teamID, err := uuid.Parse(teamIDStr)
+	if err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid team ID", nil, "")
+	}
+
+	if err := a.verifyTeamAccess(r, teamID, orgID, userID, userRole); err != nil {
+		return err
+	}
+
+	var req BatchTeamMembersRequest
+	if err := r.Decode(&req, "json"); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid request body", nil, "")
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(req.UserIDs))
+	for _, idStr := range req.UserIDs {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid user ID: "+idStr, nil, "")
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	var rows []teamMemberListRow
+	if err := a.DB.Table("team_members").
+		Joins("JOIN users ON users.id = team_members.user_id").
+		Where("team_members.team_id = ? AND team_members.user_id IN ?", teamID, userIDs).
+		Select(`
+			team_members.id as id,
+			team_members.user_id as user_id,
+			users.full_name as full_name,
+			users.email as email,
+			team_members.role as role,
+			users.is_available as is_available,
+			team_members.last_assigned_at as last_assigned_at
+		`).
+		Scan(&rows).Error; err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to load team members", nil, "")
+	}
+
+	members := make([]TeamMemberResponse, len(rows))
+	for i, row := range rows {
+		members[i] = TeamMemberResponse{
+			ID:             row.ID,
+			UserID:         row.UserID,
+			FullName:       row.FullName,
+			Email:          row.Email,
+			Role:           row.Role,
+			IsAvailable:    row.IsAvailable,
+			LastAssignedAt: row.LastAssignedAt,
+		}
+	}
+
+	return r.SendEnvelope(map[string]interface{}{"members": members})
+}
+
+// verifyTeamAccess checks that a team exists within the org and, for
+// non-admins, that the user belongs to it. On failure it sends the
+// appropriate error envelope itself and returns the resulting error, which
+// the caller should propagate with `return`.
+func (a *App) verifyTeamAccess(r *fastglue.Request, teamID, orgID, userID uuid.UUID, userRole string) error {
+	team, err := a.loadTeamWithMembers(r, teamID, orgID)
+	if err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
 	}
 
-	// Check access for non-admin users
 	if userRole != "admin" {
 		hasAccess := false
 		for _, m := range team.Members {
@@ -289,20 +568,7 @@ func (a *App) ListTeamMembers(r *fastglue.Request) error {
 		}
 	}
 
-	members := make([]TeamMemberResponse, len(team.Members))
-	for i, m := range team.Members {
-		members[i] = TeamMemberResponse{
-			ID:             m.ID,
-			UserID:         m.UserID,
-			FullName:       m.User.FullName,
-			Email:          m.User.Email,
-			Role:           m.Role,
-			IsAvailable:    m.User.IsAvailable,
-			LastAssignedAt: m.LastAssignedAt,
-		}
-	}
-
-	return r.SendEnvelope(map[string]interface{}{"members": members})
+	return nil
 }
 
 // AddTeamMember adds a member to a team (admin or team manager)
@@ -318,14 +584,51 @@ func (a *App) AddTeamMember(r *fastglue.Request) error {
 	}
 
 	// Verify team exists
-	var team models.Team
-	if err := a.DB.Where("id = ? AND organization_id = ?", teamID, orgID).
-		Preload("Members").First(&team).Error; err != nil {
+	team, err := a.loadTeamWithMembers(r, teamID, orgID)
+	if err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
 	}
 
+	var req TeamMemberRequest
+	if err := r.Decode(&req, "json"); err != nil {
+		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid request body", nil, "")
+	}
+
+	// A valid invite join proof (hash/data or invite_id) stands in for
+	// admin/manager auth, same as Mattermost's token-based AddTeamMember.
+	// The proof also pins who is added (the caller) and what role they get
+	// (the invite's), matching AcceptTeamInvite's semantics.
+	var invite *models.TeamInvite
+	hasInviteProof := false
+	if payload, ok := a.verifyInviteData(req.InviteData, req.InviteHash); ok {
+		if payload.TeamID != teamID {
+			return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Invite is not valid for this team", nil, "")
+		}
+		inv, err := a.loadInviteFromPayload(payload)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Invite not found", nil, "")
+		}
+		invite = inv
+		hasInviteProof = true
+	} else if req.InviteID != "" {
+		var inv models.TeamInvite
+		if err := a.DB.Where("invite_id = ? AND team_id = ?", req.InviteID, teamID).First(&inv).Error; err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Invite not found", nil, "")
+		}
+		invite = &inv
+		hasInviteProof = true
+	}
+	if invite != nil {
+		if invite.ExpiresAt != nil && invite.ExpiresAt.Before(time.Now()) {
+			return r.SendErrorEnvelope(fasthttp.StatusGone, "Invite has expired", nil, "")
+		}
+		if invite.MaxUses > 0 && invite.Uses >= invite.MaxUses {
+			return r.SendErrorEnvelope(fasthttp.StatusGone, "Invite has reached its usage limit", nil, "")
+		}
+	}
+
 	// Check access
-	if userRole != "admin" {
+	if userRole != "admin" && !hasInviteProof {
 		isManager := false
 		for _, m := range team.Members {
 			if m.UserID == userID && m.Role == "manager" {
@@ -338,30 +641,43 @@ func (a *App) AddTeamMember(r *fastglue.Request) error {
 		}
 	}
 
-	var req TeamMemberRequest
-	if err := r.Decode(&req, "json"); err != nil {
-		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid request body", nil, "")
+	// A join via invite proof always admits the authenticated caller, never
+	// an arbitrary user supplied in the request body.
+	memberUserID := userID
+	if !hasInviteProof {
+		memberUserID, err = uuid.Parse(req.UserID)
+		if err != nil {
+			return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid user ID", nil, "")
+		}
 	}
 
-	memberUserID, err := uuid.Parse(req.UserID)
+	// Verify user exists in org
+	user, err := a.loadOrgUser(r, memberUserID, orgID)
 	if err != nil {
-		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid user ID", nil, "")
+		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "User not found", nil, "")
 	}
 
-	// Verify user exists in org
-	var user models.User
-	if err := a.DB.Where("id = ? AND organization_id = ?", memberUserID, orgID).First(&user).Error; err != nil {
-		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "User not found", nil, "")
+	if invite != nil && invite.AllowedEmails != "" && !emailInAllowList(user.Email, invite.AllowedEmails) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "This invite is not valid for your email address", nil, "")
 	}
 
 	// Check if already a member
-	var existingMember models.TeamMember
-	if err := a.DB.Where("team_id = ? AND user_id = ?", teamID, memberUserID).First(&existingMember).Error; err == nil {
-		return r.SendErrorEnvelope(fasthttp.StatusConflict, "User is already a member of this team", nil, "")
+	for _, m := range team.Members {
+		if m.UserID == memberUserID {
+			return r.SendErrorEnvelope(fasthttp.StatusConflict, "User is already a member of this team", nil, "")
+		}
 	}
 
-	// Validate role
+	if !isTeamEmailAllowed(user, team) {
+		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "team_domain_not_allowed", nil, "")
+	}
+
+	// Validate role: an invite proof grants the role the invite was created
+	// with, never the client-supplied one.
 	role := req.Role
+	if hasInviteProof {
+		role = invite.Role
+	}
 	if role == "" {
 		role = "agent"
 	}
@@ -369,11 +685,20 @@ func (a *App) AddTeamMember(r *fastglue.Request) error {
 		return r.SendErrorEnvelope(fasthttp.StatusBadRequest, "Invalid role. Must be 'manager' or 'agent'", nil, "")
 	}
 
-	// Non-admin managers can only add agents, not other managers
-	if userRole != "admin" && role == "manager" {
+	// Non-admin managers can only add agents, not other managers. Invite
+	// proofs are exempt: CreateTeamInvite already restricts manager-role
+	// invites to admins.
+	if userRole != "admin" && role == "manager" && !hasInviteProof {
 		return r.SendErrorEnvelope(fasthttp.StatusForbidden, "Only admins can add managers to teams", nil, "")
 	}
 
+	if invite != nil {
+		invite.Uses++
+		if err := a.DB.Save(invite).Error; err != nil {
+			a.Log.Error("Failed to record invite use", "error", err)
+		}
+	}
+
 	member := models.TeamMember{
 		TeamID: teamID,
 		UserID: memberUserID,
@@ -384,6 +709,7 @@ func (a *App) AddTeamMember(r *fastglue.Request) error {
 		a.Log.Error("Failed to add team member", "error", err)
 		return r.SendErrorEnvelope(fasthttp.StatusInternalServerError, "Failed to add member", nil, "")
 	}
+	cache.Invalidate[*models.Team](r.RequestCtx, teamID)
 
 	return r.SendEnvelope(map[string]interface{}{"member": TeamMemberResponse{
 		ID:          member.ID,
@@ -414,9 +740,8 @@ func (a *App) RemoveTeamMember(r *fastglue.Request) error {
 	}
 
 	// Verify team exists
-	var team models.Team
-	if err := a.DB.Where("id = ? AND organization_id = ?", teamID, orgID).
-		Preload("Members").First(&team).Error; err != nil {
+	team, err := a.loadTeamWithMembers(r, teamID, orgID)
+	if err != nil {
 		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Team not found", nil, "")
 	}
 
@@ -450,6 +775,7 @@ func (a *App) RemoveTeamMember(r *fastglue.Request) error {
 	if result.RowsAffected == 0 {
 		return r.SendErrorEnvelope(fasthttp.StatusNotFound, "Member not found in team", nil, "")
 	}
+	cache.Invalidate[*models.Team](r.RequestCtx, teamID)
 
 	return r.SendEnvelope(map[string]string{"message": "Member removed from team"})
 }
@@ -462,6 +788,7 @@ func buildTeamResponse(team *models.Team, includeMembers bool) TeamResponse {
 		Description:        team.Description,
 		AssignmentStrategy: team.AssignmentStrategy,
 		IsActive:           team.IsActive,
+		AllowedDomains:     team.AllowedDomains,
 		MemberCount:        len(team.Members),
 		CreatedAt:          team.CreatedAt,
 		UpdatedAt:          team.UpdatedAt,
@@ -486,3 +813,48 @@ func buildTeamResponse(team *models.Team, includeMembers bool) TeamResponse {
 
 	return resp
 }
+
+// normalizeAllowedDomains lowercases, strips leading "@", splits on
+// whitespace/commas and drops empty tokens, returning a clean
+// space-separated domain list for storage.
+func normalizeAllowedDomains(raw string) string {
+	fields := strings.FieldsFunc(raw, func(c rune) bool {
+		return c == ',' || c == ' ' || c == '\t' || c == '\n'
+	})
+
+	domains := make([]string, 0, len(fields))
+	for _, f := range fields {
+		d := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(f), "@"))
+		if d != "" {
+			domains = append(domains, d)
+		}
+	}
+
+	return strings.Join(domains, " ")
+}
+
+// isTeamEmailAllowed reports whether a user's email domain is permitted to
+// join the given team. An empty AllowedDomains list or an SSO/LDAP-backed
+// user allows any domain.
+func isTeamEmailAllowed(user *models.User, team *models.Team) bool {
+	if team.AllowedDomains == "" {
+		return true
+	}
+	if user.AuthSource == "saml" || user.AuthSource == "ldap" {
+		return true
+	}
+
+	parts := strings.SplitN(user.Email, "@", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	domain := strings.ToLower(parts[1])
+
+	for _, allowed := range strings.Fields(team.AllowedDomains) {
+		if allowed == domain {
+			return true
+		}
+	}
+
+	return false
+}